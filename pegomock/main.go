@@ -0,0 +1,81 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command pegomock generates mock implementations of Go interfaces.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/petergtz/pegomock/pegomock/mockgen"
+)
+
+var (
+	source      = flag.String("source", "", "(source mode) The file to read interfaces from.")
+	destination = flag.String("destination", "", "Output file; defaults to mock_<name>_test.go in the current directory, where <name> is the source file name or the lower-cased interface name.")
+	packageOut  = flag.String("package", "", "Package of the generated code. It has no default and must be given.")
+	selfPackage = flag.String("self_package", "", "The full package import path for the generated code. Used to detect self-import.")
+	debugParser = flag.Bool("debug_parser", false, "Print out parser results to stdout before generating the mock.")
+
+	unrollVariadic = flag.Bool("unroll-variadic", true, "Forward each element of a variadic argument individually to Invoke/Verify, instead of as a single slice argument.")
+	withExpecter   = flag.Bool("with-expecter", false, "Generate a typed EXPECT() API alongside the existing Verifier.")
+
+	replaceType replaceTypeFlag
+)
+
+func init() {
+	flag.Var(&replaceType, "replace-type", "Rewrite a type encountered while generating the mock, as srcPkg.Type=dstPkg.Type or srcPkg=dstPkg. May be repeated.")
+}
+
+// replaceTypeFlag collects repeated -replace-type occurrences into the
+// []mockgen.TypeReplacement that generateMockSourceCode expects.
+type replaceTypeFlag []mockgen.TypeReplacement
+
+func (f *replaceTypeFlag) String() string {
+	return fmt.Sprint([]mockgen.TypeReplacement(*f))
+}
+
+func (f *replaceTypeFlag) Set(value string) error {
+	r, err := mockgen.ParseTypeReplacement(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, r)
+	return nil
+}
+
+func main() {
+	flag.Parse()
+
+	var args []string
+	if *source != "" {
+		args = []string{*source}
+	} else {
+		args = flag.Args()
+	}
+
+	mockgen.GenerateMockFileInOutputDir(
+		args,
+		"",
+		*destination,
+		*packageOut,
+		*selfPackage,
+		*debugParser,
+		*unrollVariadic,
+		replaceType,
+		*withExpecter,
+		os.Stdout)
+}
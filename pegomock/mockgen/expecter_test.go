@@ -0,0 +1,82 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/petergtz/pegomock/pegomock/mockgen/model"
+)
+
+func TestGenerateExpecterMethodAcceptsMatchersAndUnrollsVariadics(t *testing.T) {
+	g := &generator{withExpecter: true, unrollVariadic: true}
+	method := &model.Method{
+		Name: "Log",
+		In:   []*model.Parameter{{Name: "level", Type: model.PredeclaredType("string")}},
+		Variadic: &model.Parameter{
+			Name: "args",
+			Type: model.PredeclaredType("interface{}"),
+		},
+	}
+
+	g.GenerateExpecterMethod("Logger", "", "", method, "")
+	out := g.buf.String()
+
+	if !strings.Contains(out, "level interface{}") {
+		t.Fatalf("expecter method should accept an interface{} in place of the concrete param type, got:\n%v", out)
+	}
+	if !strings.Contains(out, "args ...interface{}") {
+		t.Fatalf("expecter method should accept a variadic interface{}, got:\n%v", out)
+	}
+	if !strings.Contains(out, "pegomockMatcherFor(level)") {
+		t.Fatalf("expecter method should wrap fixed args via pegomockMatcherFor, got:\n%v", out)
+	}
+	if !strings.Contains(out, "pegomockMatcherFor(_x)") {
+		t.Fatalf("expecter method should wrap each unrolled variadic element via pegomockMatcherFor, got:\n%v", out)
+	}
+	if !strings.Contains(out, "pegomock.GetGenericMockFrom(e.mock).Invoke(") {
+		t.Fatalf("expecter method should forward through Invoke rather than the concretely typed method, got:\n%v", out)
+	}
+}
+
+// TestGenerateExpecterMethodRespectsUnrollVariadicFlag ensures that with
+// --unroll-variadic=false, EXPECT() passes the whole variadic slice to Invoke
+// as a single matcher-wrapped argument, just as GenerateMockMethod and
+// GenerateVerifierMethod pass it as a single (unwrapped) argument in that
+// mode. Unrolling one side and not the
+// other would produce an Invoke call with the wrong number of arguments, so
+// the stub set up via EXPECT() would never match the real call.
+func TestGenerateExpecterMethodRespectsUnrollVariadicFlag(t *testing.T) {
+	g := &generator{withExpecter: true, unrollVariadic: false}
+	method := &model.Method{
+		Name: "Log",
+		In:   []*model.Parameter{{Name: "level", Type: model.PredeclaredType("string")}},
+		Variadic: &model.Parameter{
+			Name: "args",
+			Type: model.PredeclaredType("interface{}"),
+		},
+	}
+
+	g.GenerateExpecterMethod("Logger", "", "", method, "")
+	out := g.buf.String()
+
+	if strings.Contains(out, "_s := []interface{}") {
+		t.Fatalf("expecter method should not unroll the variadic when unrollVariadic is false, got:\n%v", out)
+	}
+	if !strings.Contains(out, "Invoke(\"Log\", pegomockMatcherFor(level), pegomockMatcherFor(args))") {
+		t.Fatalf("expecter method should pass the whole variadic slice as one matcher-wrapped argument, got:\n%v", out)
+	}
+}
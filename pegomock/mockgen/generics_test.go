@@ -0,0 +1,40 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockgen
+
+import (
+	"testing"
+
+	"github.com/petergtz/pegomock/pegomock/mockgen/model"
+)
+
+func TestTypeParamsDeclAndUse(t *testing.T) {
+	g := new(generator)
+
+	if decl := g.typeParamsDecl(nil); decl != "" {
+		t.Fatalf("typeParamsDecl(nil) = %q, want empty string", decl)
+	}
+	if use := typeParamsUse(nil); use != "" {
+		t.Fatalf("typeParamsUse(nil) = %q, want empty string", use)
+	}
+
+	params := []*model.Parameter{{Name: "T", Type: model.PredeclaredType("any")}}
+	if decl := g.typeParamsDecl(params); decl != "[T any]" {
+		t.Fatalf("typeParamsDecl(...) = %q, want %q", decl, "[T any]")
+	}
+	if use := typeParamsUse(params); use != "[T]" {
+		t.Fatalf("typeParamsUse(...) = %q, want %q", use, "[T]")
+	}
+}
@@ -18,9 +18,6 @@
 // MockGen generates mock implementations of Go interfaces.
 package mockgen
 
-// TODO: This does not support recursive embedded interfaces.
-// TODO: This does not support embedding package-local interfaces in a separate file.
-
 import (
 	"bytes"
 	"fmt"
@@ -42,7 +39,7 @@ import (
 const importPath = "github.com/petergtz/pegomock"
 
 func GenerateMock(packagePath, interfaceName, outputDirPath, packageOut string) (bool, string) {
-	output := generateMockSourceCode([]string{packagePath, interfaceName}, packageOut, "", false, os.Stdout)
+	output := generateMockSourceCode([]string{packagePath, interfaceName}, packageOut, "", false, true, nil, false, os.Stdout)
 	outputFilepath := outputFilePath([]string{packagePath, interfaceName}, outputDirPath, "") // <- adjust last param
 
 	existingFileContent, err := ioutil.ReadFile(outputFilepath)
@@ -71,6 +68,9 @@ func GenerateMockFileInOutputDir(
 	packageOut string,
 	selfPackage string,
 	debugParser bool,
+	unrollVariadic bool,
+	replaceTypes []TypeReplacement,
+	withExpecter bool,
 	out io.Writer) {
 	GenerateMockFile(
 		args,
@@ -78,6 +78,9 @@ func GenerateMockFileInOutputDir(
 		packageOut,
 		selfPackage,
 		debugParser,
+		unrollVariadic,
+		replaceTypes,
+		withExpecter,
 		out)
 }
 
@@ -91,8 +94,8 @@ func outputFilePath(args []string, outputDirPath string, outputFilePathOverride
 	}
 }
 
-func GenerateMockFile(args []string, outputFilePath string, packageOut string, selfPackage string, debugParser bool, out io.Writer) {
-	output := generateMockSourceCode(args, packageOut, selfPackage, debugParser, out)
+func GenerateMockFile(args []string, outputFilePath string, packageOut string, selfPackage string, debugParser bool, unrollVariadic bool, replaceTypes []TypeReplacement, withExpecter bool, out io.Writer) {
+	output := generateMockSourceCode(args, packageOut, selfPackage, debugParser, unrollVariadic, replaceTypes, withExpecter, out)
 
 	err := ioutil.WriteFile(outputFilePath, output, 0664)
 	if err != nil {
@@ -100,7 +103,7 @@ func GenerateMockFile(args []string, outputFilePath string, packageOut string, s
 	}
 }
 
-func generateMockSourceCode(args []string, packageOut string, selfPackage string, debugParser bool, out io.Writer) []byte {
+func generateMockSourceCode(args []string, packageOut string, selfPackage string, debugParser bool, unrollVariadic bool, replaceTypes []TypeReplacement, withExpecter bool, out io.Writer) []byte {
 	var err error
 
 	var ast *model.Package
@@ -123,7 +126,7 @@ func generateMockSourceCode(args []string, packageOut string, selfPackage string
 		ast.Print(out)
 	}
 
-	output, err := generateOutput(ast, src, packageOut, selfPackage)
+	output, err := generateOutput(ast, src, packageOut, selfPackage, unrollVariadic, replaceTypes, withExpecter)
 	if err != nil {
 		panic(fmt.Errorf("Failed generating mock: %v", err))
 	}
@@ -142,6 +145,60 @@ type generator struct {
 	indent string
 
 	packageMap map[string]string // map from import path to package name
+
+	// unrollVariadic controls how variadic parameters are forwarded to
+	// Invoke/Verify. When true (the default), each element of the variadic
+	// argument is appended individually, so a matcher can be supplied for
+	// each element. When false, the variadic slice is passed as a single
+	// argument, so a matcher can be applied to the whole slice at once.
+	unrollVariadic bool
+
+	// replaceTypes rewrites types encountered while rendering a method
+	// signature, as configured via --replace-type.
+	replaceTypes []TypeReplacement
+
+	// withExpecter controls whether a typed EXPECT() API is generated
+	// alongside the existing Verifier, as configured via --with-expecter.
+	withExpecter bool
+}
+
+// TypeReplacement describes a single --replace-type rule: occurrences of
+// SourceType in SourcePackage are rendered as DestType in DestPackage
+// instead. SourceType and DestType are both empty for a whole-package
+// rename (--replace-type=srcPkg=dstPkg), which leaves type names untouched
+// but rewrites the package they're imported from.
+type TypeReplacement struct {
+	SourcePackage string
+	SourceType    string
+	DestPackage   string
+	DestType      string
+}
+
+// ParseTypeReplacement parses a --replace-type argument of the form
+// "srcPkg.Type=dstPkg.Type" or, for renaming a whole package, "srcPkg=dstPkg".
+func ParseTypeReplacement(arg string) (TypeReplacement, error) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 {
+		return TypeReplacement{}, fmt.Errorf("replace-type %q: expected the form srcPkg.Type=dstPkg.Type", arg)
+	}
+	src, srcType := splitPackageAndType(parts[0])
+	dst, dstType := splitPackageAndType(parts[1])
+	if (srcType == "") != (dstType == "") {
+		return TypeReplacement{}, fmt.Errorf("replace-type %q: either both sides name a type, or neither does", arg)
+	}
+	return TypeReplacement{SourcePackage: src, SourceType: srcType, DestPackage: dst, DestType: dstType}, nil
+}
+
+// splitPackageAndType splits "some/pkg.Type" into ("some/pkg", "Type"). Since
+// import paths may themselves contain dots, only the last path segment is
+// considered when looking for the package/type separator.
+func splitPackageAndType(s string) (pkg string, typeName string) {
+	lastSlash := strings.LastIndex(s, "/")
+	rest := s[lastSlash+1:]
+	if dot := strings.Index(rest, "."); dot >= 0 {
+		return s[:lastSlash+1+dot], rest[dot+1:]
+	}
+	return s, ""
 }
 
 func (g *generator) p(format string, args ...interface{}) *generator {
@@ -193,8 +250,11 @@ func sanitize(s string) string {
 	return t
 }
 
-func generateOutput(ast *model.Package, source string, packageOut string, selfPackage string) ([]byte, error) {
+func generateOutput(ast *model.Package, source string, packageOut string, selfPackage string, unrollVariadic bool, replaceTypes []TypeReplacement, withExpecter bool) ([]byte, error) {
 	g := new(generator)
+	g.unrollVariadic = unrollVariadic
+	g.replaceTypes = replaceTypes
+	g.withExpecter = withExpecter
 	if err := g.Generate(source, ast, packageOut, selfPackage); err != nil {
 		return nil, fmt.Errorf("Failed generating mock: %v", err)
 	}
@@ -207,7 +267,10 @@ func (g *generator) Generate(source string, pkg *model.Package, pkgName string,
 	g.p("")
 
 	// Get all required imports, and generate unique names for them all.
-	im := pkg.Imports()
+	// Derived from what --replace-type rules actually produce, rather than
+	// guessed from the rules themselves, so a substituted source package
+	// never lingers and a destination package is never missing.
+	im := g.collectImports(pkg)
 	im[importPath] = true
 	g.packageMap = make(map[string]string, len(im))
 	localNames := make(map[string]bool, len(im))
@@ -246,6 +309,20 @@ func (g *generator) Generate(source string, pkg *model.Package, pkgName string,
 	g.out()
 	g.p(")")
 
+	if g.withExpecter {
+		g.p("")
+		g.p("// pegomockMatcherFor lets an EXPECT() call accept either a literal value")
+		g.p("// or a pegomock.Matcher for the same parameter.")
+		g.p("func pegomockMatcherFor(param interface{}) pegomock.Matcher {")
+		g.in()
+		g.p("if matcher, ok := param.(pegomock.Matcher); ok {")
+		g.in().p("return matcher").out()
+		g.p("}")
+		g.p("return pegomock.Eq(param)")
+		g.out()
+		g.p("}")
+	}
+
 	for _, iface := range pkg.Interfaces {
 		g.GenerateMockInterface(iface, selfPackage)
 	}
@@ -258,55 +335,119 @@ func mockName(typeName string) string {
 	return "Mock" + typeName
 }
 
+// typeParamsDecl renders an interface's type parameter list the way it needs
+// to appear in a type or function declaration, e.g. "[T any]". It is empty
+// for non-generic interfaces.
+func (g *generator) typeParamsDecl(params []*model.Parameter) string {
+	if len(params) == 0 {
+		return ""
+	}
+	decls := make([]string, len(params))
+	for i, p := range params {
+		decls[i] = p.Name + " " + g.replaceType(p.Type).String(g.packageMap, "")
+	}
+	return "[" + strings.Join(decls, ", ") + "]"
+}
+
+// typeParamsUse renders an interface's type parameters the way they need to
+// appear when instantiating/using the generic type, e.g. "[T]". It is empty
+// for non-generic interfaces.
+func typeParamsUse(params []*model.Parameter) string {
+	if len(params) == 0 {
+		return ""
+	}
+	names := make([]string, len(params))
+	for i, p := range params {
+		names[i] = p.Name
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
 func (g *generator) GenerateMockInterface(iface *model.Interface, selfPackage string) {
 	mockType := mockName(iface.Name)
+	typeParamsDecl := g.typeParamsDecl(iface.TypeParams)
+	typeArgs := typeParamsUse(iface.TypeParams)
 
 	g.p("")
 	g.p("// Mock of %v interface", iface.Name)
-	g.p("type %v struct {", mockType)
+	g.p("type %v%v struct {", mockType, typeParamsDecl)
 	g.in().p("fail func(message string, callerSkip ...int)").out()
 	g.p("}")
 	g.p("")
 
-	g.p("func New%v() *%v {", mockType, mockType)
-	g.in().p("return &%v{fail: pegomock.GlobalFailHandler}", mockType).out()
+	g.p("func New%v%v() *%v%v {", mockType, typeParamsDecl, mockType, typeArgs)
+	g.in().p("return &%v%v{fail: pegomock.GlobalFailHandler}", mockType, typeArgs).out()
 	g.p("}")
 	g.p("")
 
 	for _, method := range iface.Methods {
-		g.GenerateMockMethod(mockType, method, selfPackage).p("")
+		g.GenerateMockMethod(mockType, typeArgs, method, selfPackage).p("")
 	}
-	g.p("type Verifier%v struct {", iface.Name)
+	g.p("type Verifier%v%v struct {", iface.Name, typeParamsDecl)
 	g.in().
-		p("mock *Mock%v", iface.Name).
+		p("mock *Mock%v%v", iface.Name, typeArgs).
 		p("invocationCountMatcher pegomock.Matcher").
 		p("inOrderContext *pegomock.InOrderContext").
 		out()
 	g.p("}")
 	g.p("")
-	g.p("func (mock *Mock%v) VerifyWasCalledOnce() *Verifier%v {", iface.Name, iface.Name)
-	g.in().p("return &Verifier%v{mock, pegomock.Times(1), nil}", iface.Name).out()
+	g.p("func (mock *Mock%v%v) VerifyWasCalledOnce() *Verifier%v%v {", iface.Name, typeArgs, iface.Name, typeArgs)
+	g.in().p("return &Verifier%v%v{mock, pegomock.Times(1), nil}", iface.Name, typeArgs).out()
+	g.p("}")
+	g.p("")
+	g.p("func (mock *Mock%v%v) VerifyWasCalled(invocationCountMatcher pegomock.Matcher) *Verifier%v%v {", iface.Name, typeArgs, iface.Name, typeArgs)
+	g.in().p("return &Verifier%v%v{mock, invocationCountMatcher, nil}", iface.Name, typeArgs).out()
 	g.p("}")
 	g.p("")
-	g.p("func (mock *Mock%v) VerifyWasCalled(invocationCountMatcher pegomock.Matcher) *Verifier%v {", iface.Name, iface.Name)
-	g.in().p("return &Verifier%v{mock, invocationCountMatcher, nil}", iface.Name).out()
+	g.p("func (mock *Mock%v%v) VerifyWasCalledInOrder(invocationCountMatcher pegomock.Matcher, inOrderContext *pegomock.InOrderContext) *Verifier%v%v {", iface.Name, typeArgs, iface.Name, typeArgs)
+	g.in().p("return &Verifier%v%v{mock, invocationCountMatcher, inOrderContext}", iface.Name, typeArgs).out()
+	g.p("}")
+	g.p("")
+	for _, method := range iface.Methods {
+		g.GenerateVerifierMethod(iface.Name, typeArgs, method, selfPackage).p("")
+	}
+
+	if g.withExpecter {
+		g.GenerateExpecter(iface, selfPackage)
+	}
+}
+
+// GenerateExpecter generates a typed EXPECT() API for iface, gated behind
+// --with-expecter. It mirrors the generated Mock/Verifier pair, but lets
+// callers write mock.EXPECT().Method(args).ThenReturn(result) with return
+// types matching the real method signature, instead of going through
+// pegomock.When(mock.Method(args)) directly. Its parameters are interface{}
+// rather than the real parameter types, so each accepts either a value or a
+// pegomock.Matcher.
+func (g *generator) GenerateExpecter(iface *model.Interface, selfPackage string) {
+	mockType := mockName(iface.Name)
+	expecterType := mockType + "Expecter"
+	typeParamsDecl := g.typeParamsDecl(iface.TypeParams)
+	typeArgs := typeParamsUse(iface.TypeParams)
+
+	g.p("func (mock *%v%v) EXPECT() *%v%v {", mockType, typeArgs, expecterType, typeArgs)
+	g.in().p("return &%v%v{mock}", expecterType, typeArgs).out()
 	g.p("}")
 	g.p("")
-	g.p("func (mock *Mock%v) VerifyWasCalledInOrder(invocationCountMatcher pegomock.Matcher, inOrderContext *pegomock.InOrderContext) *Verifier%v {", iface.Name, iface.Name)
-	g.in().p("return &Verifier%v{mock, invocationCountMatcher, inOrderContext}", iface.Name).out()
+	g.p("type %v%v struct {", expecterType, typeParamsDecl)
+	g.in().p("mock *%v%v", mockType, typeArgs).out()
 	g.p("}")
 	g.p("")
+
 	for _, method := range iface.Methods {
-		g.GenerateVerifierMethod(iface.Name, method, selfPackage).p("")
+		g.GenerateExpecterMethod(iface.Name, typeParamsDecl, typeArgs, method, selfPackage).p("")
 	}
 }
 
 // GenerateMockMethod generates a mock method implementation.
 // If non-empty, pkgOverride is the package in which unqualified types reside.
-func (g *generator) GenerateMockMethod(mockType string, method *model.Method, pkgOverride string) *generator {
-	_, _, argString, rets, retString, callArgs := getStuff(method, g, pkgOverride)
-	g.p("func (mock *%v) %v(%v)%v {", mockType, method.Name, argString, retString)
+func (g *generator) GenerateMockMethod(mockType string, typeArgs string, method *model.Method, pkgOverride string) *generator {
+	_, argNames, argString, rets, retString, callArgs := getStuff(method, g, pkgOverride)
+	g.p("func (mock *%v%v) %v(%v)%v {", mockType, typeArgs, method.Name, argString, retString)
 	g.in()
+	if method.Variadic != nil && g.unrollVariadic {
+		callArgs = g.unrollVariadicArgs(argNames)
+	}
 	r := ""
 	if len(method.Out) > 0 {
 		r = "result :="
@@ -339,10 +480,13 @@ func resultCast(returnTypes []string) string {
 	return strings.Join(castedResults, ", ")
 }
 
-func (g *generator) GenerateVerifierMethod(interfaceName string, method *model.Method, pkgOverride string) *generator {
-	_, _, argString, rets, retString, callArgs := getStuff(method, g, pkgOverride)
+func (g *generator) GenerateVerifierMethod(interfaceName string, typeArgs string, method *model.Method, pkgOverride string) *generator {
+	_, argNames, argString, rets, retString, callArgs := getStuff(method, g, pkgOverride)
 
-	g.p("func (verifier *Verifier%v) %v(%v)%v {", interfaceName, method.Name, argString, retString)
+	g.p("func (verifier *Verifier%v%v) %v(%v)%v {", interfaceName, typeArgs, method.Name, argString, retString)
+	if method.Variadic != nil && g.unrollVariadic {
+		callArgs = g.unrollVariadicArgs(argNames)
+	}
 	g.p("pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, \"%v\", %v)", method.Name, callArgs)
 
 	if len(method.Out) > 0 {
@@ -358,6 +502,84 @@ func (g *generator) GenerateVerifierMethod(interfaceName string, method *model.M
 	return g
 }
 
+// GenerateExpecterMethod generates the typed EXPECT().Method(...) entry
+// point for method, plus the Call type it returns. The Call type has a
+// ThenReturn only if method has results, and a ThenReturnError only if its
+// last result is an error.
+func (g *generator) GenerateExpecterMethod(interfaceName string, typeParamsDecl string, typeArgs string, method *model.Method, pkgOverride string) *generator {
+	_, argNames, _, rets, _, _ := getStuff(method, g, pkgOverride)
+
+	expecterType := mockName(interfaceName) + "Expecter"
+	callType := fmt.Sprintf("%v_%v_Call", interfaceName, method.Name)
+
+	// Expecter parameters are interface{}, not the method's real parameter
+	// types: a caller needs to be able to pass either a literal value or a
+	// pegomock.Matcher, and pegomockMatcherFor tells the two apart below.
+	params := make([]string, len(argNames))
+	for i, name := range argNames {
+		params[i] = name + " interface{}"
+	}
+	if method.Variadic != nil {
+		params[len(params)-1] = argNames[len(argNames)-1] + " ...interface{}"
+	}
+
+	g.p("func (e *%v%v) %v(%v) *%v%v {", expecterType, typeArgs, method.Name, strings.Join(params, ", "), callType, typeArgs)
+	g.in()
+	var invokeArgs string
+	if method.Variadic != nil && g.unrollVariadic {
+		invokeArgs = g.unrollExpecterArgs(argNames)
+	} else {
+		matchers := make([]string, len(argNames))
+		for i, name := range argNames {
+			matchers[i] = fmt.Sprintf("pegomockMatcherFor(%v)", name)
+		}
+		invokeArgs = strings.Join(matchers, ", ")
+	}
+	g.p("return &%v%v{pegomock.When(pegomock.GetGenericMockFrom(e.mock).Invoke(\"%v\", %v))}", callType, typeArgs, method.Name, invokeArgs)
+	g.out()
+	g.p("}")
+	g.p("")
+	g.p("type %v%v struct {", callType, typeParamsDecl)
+	g.in().p("ongoingStubbing pegomock.OngoingStubbing").out()
+	g.p("}")
+
+	if len(method.Out) == 0 {
+		return g
+	}
+
+	retParams := make([]string, len(rets))
+	retValues := make([]string, len(rets))
+	for i, ret := range rets {
+		retParams[i] = fmt.Sprintf("ret%v %v", i, ret)
+		retValues[i] = fmt.Sprintf("ret%v", i)
+	}
+
+	g.p("")
+	g.p("func (call *%v%v) ThenReturn(%v) *%v%v {", callType, typeArgs, strings.Join(retParams, ", "), callType, typeArgs)
+	g.in().
+		p("call.ongoingStubbing.ThenReturn(%v)", strings.Join(retValues, ", ")).
+		p("return call").
+		out()
+	g.p("}")
+
+	if rets[len(rets)-1] == "error" {
+		g.p("")
+		g.p("func (call *%v%v) ThenReturnError(err error) *%v%v {", callType, typeArgs, callType, typeArgs)
+		g.in()
+		zeroValues := make([]string, len(rets))
+		for i, ret := range rets[:len(rets)-1] {
+			zeroValues[i] = fmt.Sprintf("*new(%v)", ret)
+		}
+		zeroValues[len(rets)-1] = "err"
+		g.p("call.ongoingStubbing.ThenReturn(%v)", strings.Join(zeroValues, ", ")).
+			p("return call")
+		g.out()
+		g.p("}")
+	}
+
+	return g
+}
+
 func getStuff(method *model.Method, g *generator, pkgOverride string) (
 	args []string,
 	argNames []string,
@@ -373,7 +595,7 @@ func getStuff(method *model.Method, g *generator, pkgOverride string) (
 		if name == "" {
 			name = fmt.Sprintf("_param%d", i)
 		}
-		ts := p.Type.String(g.packageMap, pkgOverride)
+		ts := g.replaceType(p.Type).String(g.packageMap, pkgOverride)
 		args[i] = name + " " + ts
 		argNames[i] = name
 	}
@@ -382,7 +604,7 @@ func getStuff(method *model.Method, g *generator, pkgOverride string) (
 		if name == "" {
 			name = fmt.Sprintf("_param%d", len(method.In))
 		}
-		ts := method.Variadic.Type.String(g.packageMap, pkgOverride)
+		ts := g.replaceType(method.Variadic.Type).String(g.packageMap, pkgOverride)
 		args = append(args, name+" ..."+ts)
 		argNames = append(argNames, name)
 	}
@@ -390,7 +612,7 @@ func getStuff(method *model.Method, g *generator, pkgOverride string) (
 
 	rets = make([]string, len(method.Out))
 	for i, p := range method.Out {
-		rets[i] = p.Type.String(g.packageMap, pkgOverride)
+		rets[i] = g.replaceType(p.Type).String(g.packageMap, pkgOverride)
 	}
 	retString = strings.Join(rets, ", ")
 	if len(rets) > 1 {
@@ -401,21 +623,117 @@ func getStuff(method *model.Method, g *generator, pkgOverride string) (
 	}
 
 	callArgs = strings.Join(argNames, ", ")
-	// TODO: variadic arguments
-	// if method.Variadic != nil {
-	// 	// Non-trivial. The generated code must build a []interface{},
-	// 	// but the variadic argument may be any type.
-	// 	g.p("_s := []interface{}{%s}", strings.Join(argNames[:len(argNames)-1], ", "))
-	// 	g.p("for _, _x := range %s {", argNames[len(argNames)-1])
-	// 	g.in()
-	// 	g.p("_s = append(_s, _x)")
-	// 	g.out()
-	// 	g.p("}")
-	// 	callArgs = ", _s..."
-	// }
 	return
 }
 
+// replaceType applies any matching --replace-type rule to t, substituting the
+// configured destination package/type. It recurses into every composite type
+// that can wrap a model.NamedType (pointers, slices/arrays, maps, channels,
+// funcs), so a rule matches just as well inside e.g. *internal.Thing or
+// []internal.Thing as it does for a bare internal.Thing.
+func (g *generator) replaceType(t model.Type) model.Type {
+	switch t := t.(type) {
+	case *model.NamedType:
+		return g.replaceNamedType(t)
+	case *model.PointerType:
+		return &model.PointerType{Type: g.replaceType(t.Type)}
+	case *model.ArrayType:
+		return &model.ArrayType{Len: t.Len, Type: g.replaceType(t.Type)}
+	case *model.MapType:
+		return &model.MapType{Key: g.replaceType(t.Key), Value: g.replaceType(t.Value)}
+	case *model.ChanType:
+		return &model.ChanType{Dir: t.Dir, Type: g.replaceType(t.Type)}
+	case *model.FuncType:
+		return &model.FuncType{In: g.replaceParams(t.In), Out: g.replaceParams(t.Out), Variadic: t.Variadic}
+	default:
+		return t
+	}
+}
+
+func (g *generator) replaceParams(params []*model.Parameter) []*model.Parameter {
+	replaced := make([]*model.Parameter, len(params))
+	for i, p := range params {
+		replaced[i] = &model.Parameter{Name: p.Name, Type: g.replaceType(p.Type)}
+	}
+	return replaced
+}
+
+func (g *generator) replaceNamedType(nt *model.NamedType) model.Type {
+	for _, r := range g.replaceTypes {
+		if r.SourcePackage != nt.Package {
+			continue
+		}
+		if r.SourceType != "" && r.SourceType != nt.Type {
+			continue
+		}
+		replaced := &model.NamedType{Package: r.DestPackage, Type: nt.Type}
+		if r.DestType != "" {
+			replaced.Type = r.DestType
+		}
+		return replaced
+	}
+	return nt
+}
+
+// collectImports returns the import set actually required once every
+// --replace-type rule has been applied, by replaying replaceType over each
+// method signature and collecting the imports of the results. This replaces
+// the old heuristic of add/deleting import paths named in the rules
+// themselves, which could leave a generated mock referencing a package that
+// was never actually substituted, or missing one that was.
+func (g *generator) collectImports(pkg *model.Package) map[string]bool {
+	im := make(map[string]bool)
+	for _, iface := range pkg.Interfaces {
+		for _, p := range iface.TypeParams {
+			g.replaceType(p.Type).AddImports(im)
+		}
+		for _, method := range iface.Methods {
+			for _, p := range method.In {
+				g.replaceType(p.Type).AddImports(im)
+			}
+			if method.Variadic != nil {
+				g.replaceType(method.Variadic.Type).AddImports(im)
+			}
+			for _, p := range method.Out {
+				g.replaceType(p.Type).AddImports(im)
+			}
+		}
+	}
+	return im
+}
+
+// unrollVariadicArgs emits the statements that pack the trailing variadic
+// argument's elements into an []interface{}, so each one can be matched
+// individually, and returns the call arguments to use in place of argNames.
+func (g *generator) unrollVariadicArgs(argNames []string) string {
+	variadicName := argNames[len(argNames)-1]
+	fixedArgs := argNames[:len(argNames)-1]
+	g.p("_s := []interface{}{%v}", strings.Join(fixedArgs, ", "))
+	g.p("for _, _x := range %v {", variadicName)
+	g.in().p("_s = append(_s, _x)").out()
+	g.p("}")
+	return "_s..."
+}
+
+// unrollExpecterArgs is unrollVariadicArgs' counterpart for the EXPECT() API:
+// it packs the trailing variadic argument's elements into an []interface{}
+// the same way, but also runs every element, fixed or variadic, through
+// pegomockMatcherFor first, so a pegomock.Matcher can be supplied for any of
+// them.
+func (g *generator) unrollExpecterArgs(argNames []string) string {
+	variadicName := argNames[len(argNames)-1]
+	fixedArgs := argNames[:len(argNames)-1]
+	wrappedFixed := make([]string, len(fixedArgs))
+	for i, name := range fixedArgs {
+		wrappedFixed[i] = fmt.Sprintf("pegomockMatcherFor(%v)", name)
+	}
+	g.p("_s := []interface{}{%v}", strings.Join(wrappedFixed, ", "))
+	g.p("for _, _x := range %v {", variadicName)
+	g.in().p("_s = append(_s, pegomockMatcherFor(_x))").out()
+	g.p("}")
+	return "_s..."
+}
+
 // Output returns the generator's output, formatted in the standard Go style.
 func (g *generator) Output() []byte {
 	src, err := format.Source(g.buf.Bytes())
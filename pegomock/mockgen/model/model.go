@@ -0,0 +1,276 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Based on the work done in
+// https://github.com/golang/mock/blob/d581abfc04272f381d7a05e4b80163ea4e2b9447/mockgen/model/model.go
+
+// Package model contains the model construct that mockgen uses to represent
+// interfaces as parsed from either source code or by loading the compiled
+// package, independent of how they were obtained.
+package model
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Package is a Go package. It may be a subset.
+type Package struct {
+	Name       string
+	Interfaces []*Interface
+	DotImports []string
+}
+
+// Print writes the package as a debug representation of the parsed model,
+// used by mockgen's -debug_parser flag.
+func (pkg *Package) Print(w io.Writer) {
+	fmt.Fprintf(w, "package %v\n", pkg.Name)
+	for _, intf := range pkg.Interfaces {
+		intf.Print(w)
+	}
+}
+
+// Imports returns the imports needed by pkg, expressed as a set of import paths.
+func (pkg *Package) Imports() map[string]bool {
+	im := make(map[string]bool)
+	for _, intf := range pkg.Interfaces {
+		intf.AddImports(im)
+	}
+	return im
+}
+
+// Interface is a Go interface.
+type Interface struct {
+	Name    string
+	Methods []*Method
+
+	// TypeParams holds the interface's type parameter list, e.g. the "T any"
+	// in "type Repo[T any] interface {...}". It is nil for a non-generic
+	// interface.
+	TypeParams []*Parameter
+}
+
+// Print writes a debug representation of intf to w.
+func (intf *Interface) Print(w io.Writer) {
+	fmt.Fprintf(w, "interface %v\n", intf.Name)
+	for _, m := range intf.Methods {
+		m.Print(w)
+	}
+}
+
+func (intf *Interface) AddImports(im map[string]bool) {
+	for _, p := range intf.TypeParams {
+		p.Type.AddImports(im)
+	}
+	for _, m := range intf.Methods {
+		m.AddImports(im)
+	}
+}
+
+// Method is a single method of an interface.
+type Method struct {
+	Name     string
+	In, Out  []*Parameter
+	Variadic *Parameter // may be nil
+}
+
+// Print writes a debug representation of m to w.
+func (m *Method) Print(w io.Writer) {
+	fmt.Fprintf(w, "- method %v\n", m.Name)
+	if len(m.In) > 0 {
+		fmt.Fprintf(w, "  in:\n")
+		for _, p := range m.In {
+			p.Print(w)
+		}
+	}
+	if m.Variadic != nil {
+		fmt.Fprintf(w, "  variadic:\n")
+		m.Variadic.Print(w)
+	}
+	if len(m.Out) > 0 {
+		fmt.Fprintf(w, "  out:\n")
+		for _, p := range m.Out {
+			p.Print(w)
+		}
+	}
+}
+
+func (m *Method) AddImports(im map[string]bool) {
+	for _, p := range m.In {
+		p.Type.AddImports(im)
+	}
+	if m.Variadic != nil {
+		m.Variadic.Type.AddImports(im)
+	}
+	for _, p := range m.Out {
+		p.Type.AddImports(im)
+	}
+}
+
+// Parameter is an argument or return parameter of a method.
+type Parameter struct {
+	Name string // may be empty
+	Type Type
+}
+
+// Print writes a debug representation of p to w.
+func (p *Parameter) Print(w io.Writer) {
+	fmt.Fprintf(w, "    - %v %v\n", p.Name, p.Type.String(nil, ""))
+}
+
+// Type is an entry in the type system of Go, as understood by mockgen. Every
+// concrete type it can represent knows how to render itself as source code,
+// given a map of import path to local alias, and how to contribute to a
+// package's required imports.
+type Type interface {
+	String(pm map[string]string, pkgOverride string) string
+	AddImports(im map[string]bool)
+}
+
+// ArrayType is an array or slice type.
+type ArrayType struct {
+	Len  int // -1 for a slice, >= 0 for an array
+	Type Type
+}
+
+func (at *ArrayType) String(pm map[string]string, pkgOverride string) string {
+	if at.Len < 0 {
+		return "[]" + at.Type.String(pm, pkgOverride)
+	}
+	return fmt.Sprintf("[%d]%v", at.Len, at.Type.String(pm, pkgOverride))
+}
+
+func (at *ArrayType) AddImports(im map[string]bool) { at.Type.AddImports(im) }
+
+// MapType is a map type.
+type MapType struct {
+	Key, Value Type
+}
+
+func (mt *MapType) String(pm map[string]string, pkgOverride string) string {
+	return fmt.Sprintf("map[%v]%v", mt.Key.String(pm, pkgOverride), mt.Value.String(pm, pkgOverride))
+}
+
+func (mt *MapType) AddImports(im map[string]bool) {
+	mt.Key.AddImports(im)
+	mt.Value.AddImports(im)
+}
+
+// NamedType is an exported type in a package, e.g. time.Time or io.Reader,
+// or a predeclared error.
+type NamedType struct {
+	Package string // may be empty for a predeclared type such as error
+	Type    string
+}
+
+func (nt *NamedType) String(pm map[string]string, pkgOverride string) string {
+	if nt.Package == "" || nt.Package == pkgOverride {
+		return nt.Type
+	}
+	if alias, ok := pm[nt.Package]; ok {
+		return alias + "." + nt.Type
+	}
+	return path.Base(nt.Package) + "." + nt.Type
+}
+
+func (nt *NamedType) AddImports(im map[string]bool) {
+	if nt.Package != "" {
+		im[nt.Package] = true
+	}
+}
+
+// PointerType is a pointer to another type.
+type PointerType struct {
+	Type Type
+}
+
+func (pt *PointerType) String(pm map[string]string, pkgOverride string) string {
+	return "*" + pt.Type.String(pm, pkgOverride)
+}
+
+func (pt *PointerType) AddImports(im map[string]bool) { pt.Type.AddImports(im) }
+
+// PredeclaredType is a predeclared type such as int, string, or any.
+type PredeclaredType string
+
+func (pt PredeclaredType) String(map[string]string, string) string { return string(pt) }
+func (pt PredeclaredType) AddImports(map[string]bool)              {}
+
+// ChanDir is the direction of a channel type.
+type ChanDir int
+
+const (
+	RecvDir ChanDir = iota
+	SendDir
+	BothDir
+)
+
+// ChanType is a channel type.
+type ChanType struct {
+	Dir  ChanDir
+	Type Type
+}
+
+func (ct *ChanType) String(pm map[string]string, pkgOverride string) string {
+	switch ct.Dir {
+	case RecvDir:
+		return "<-chan " + ct.Type.String(pm, pkgOverride)
+	case SendDir:
+		return "chan<- " + ct.Type.String(pm, pkgOverride)
+	default:
+		return "chan " + ct.Type.String(pm, pkgOverride)
+	}
+}
+
+func (ct *ChanType) AddImports(im map[string]bool) { ct.Type.AddImports(im) }
+
+// FuncType is a function type, e.g. a parameter whose type is itself a func.
+type FuncType struct {
+	In, Out  []*Parameter
+	Variadic bool
+}
+
+func (ft *FuncType) String(pm map[string]string, pkgOverride string) string {
+	in := make([]string, len(ft.In))
+	for i, p := range ft.In {
+		if ft.Variadic && i == len(ft.In)-1 {
+			in[i] = "..." + p.Type.String(pm, pkgOverride)
+			continue
+		}
+		in[i] = p.Type.String(pm, pkgOverride)
+	}
+	out := make([]string, len(ft.Out))
+	for i, p := range ft.Out {
+		out[i] = p.Type.String(pm, pkgOverride)
+	}
+	switch len(out) {
+	case 0:
+		return fmt.Sprintf("func(%v)", strings.Join(in, ", "))
+	case 1:
+		return fmt.Sprintf("func(%v) %v", strings.Join(in, ", "), out[0])
+	default:
+		return fmt.Sprintf("func(%v) (%v)", strings.Join(in, ", "), strings.Join(out, ", "))
+	}
+}
+
+func (ft *FuncType) AddImports(im map[string]bool) {
+	for _, p := range ft.In {
+		p.Type.AddImports(im)
+	}
+	for _, p := range ft.Out {
+		p.Type.AddImports(im)
+	}
+}
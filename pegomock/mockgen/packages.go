@@ -0,0 +1,347 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/petergtz/pegomock/pegomock/mockgen/model"
+)
+
+// loadMode is the set of information packages.Load needs to collect in order
+// for us to resolve every interface method signature, including ones coming
+// from embedded interfaces defined in other packages or files.
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+	packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps
+
+// ParseFile loads the package containing source and turns the interfaces it
+// declares into a *model.Package. Unlike the previous go/build-based parser,
+// this resolves imports, vendored dependencies and type aliases the same way
+// the Go compiler would, because it goes through go/packages rather than
+// walking the AST by hand.
+func ParseFile(source string) (*model.Package, error) {
+	pkg, err := loadPackage(filepath.Dir(source), "file="+source)
+	if err != nil {
+		return nil, err
+	}
+	interfaceNames, err := interfaceNamesInFile(pkg, source)
+	if err != nil {
+		return nil, err
+	}
+	if len(interfaceNames) == 0 {
+		return &model.Package{Name: pkg.Types.Name()}, nil
+	}
+	return packageFromTypes(pkg.Types.Name(), pkg, interfaceNames...)
+}
+
+// interfaceNamesInFile returns the names of every interface type declared
+// directly in source, as opposed to anywhere else in the package that
+// contains it. Without this, mocking a single file in a multi-file package
+// would pick up interfaces declared in its sibling files too.
+func interfaceNamesInFile(pkg *packages.Package, source string) ([]string, error) {
+	absSource, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+	var file *ast.File
+	for _, f := range pkg.Syntax {
+		name := pkg.Fset.Position(f.Pos()).Filename
+		absName, err := filepath.Abs(name)
+		if err != nil {
+			return nil, err
+		}
+		if absName == absSource {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return nil, fmt.Errorf("cannot find %v among the parsed files of package %v", source, pkg.PkgPath)
+	}
+
+	scope := pkg.Types.Scope()
+	var names []string
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			obj := scope.Lookup(typeSpec.Name.Name)
+			if obj == nil {
+				continue
+			}
+			if _, ok := obj.Type().Underlying().(*types.Interface); ok {
+				names = append(names, typeSpec.Name.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// Reflect loads importPath the same way ParseFile loads a single file, then
+// extracts the named interfaces from its type information. The name
+// "Reflect" is kept for backwards compatibility with callers, but no
+// reflection and no separate compile-and-run step is involved anymore: the
+// type checker's output is enough to describe the interfaces.
+func Reflect(importPath string, interfaceNames []string) (*model.Package, error) {
+	pkg, err := loadPackage("", importPath)
+	if err != nil {
+		return nil, err
+	}
+	return packageFromTypes(pkg.Types.Name(), pkg, interfaceNames...)
+}
+
+func loadPackage(dir string, pattern string) (*packages.Package, error) {
+	pkgs, err := packages.Load(&packages.Config{Mode: loadMode, Dir: dir}, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("loading package %q: %v", pattern, err)
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("package %q has errors", pattern)
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("pattern %q resolved to %v packages, expected exactly one", pattern, len(pkgs))
+	}
+	return pkgs[0], nil
+}
+
+// packageFromTypes walks pkg's scope and builds a *model.Package out of every
+// interface type declared in it. If interfaceNames is given, only those
+// interfaces are included; otherwise all exported interfaces are.
+func packageFromTypes(pkgName string, pkg *packages.Package, interfaceNames ...string) (*model.Package, error) {
+	scope := pkg.Types.Scope()
+	names := interfaceNames
+	if len(names) == 0 {
+		names = scope.Names()
+	}
+
+	c := &typeConverter{self: pkg}
+	var interfaces []*model.Interface
+	for _, name := range names {
+		obj := scope.Lookup(name)
+		if obj == nil {
+			return nil, fmt.Errorf("cannot find interface %v in package %v", name, pkg.PkgPath)
+		}
+		if len(interfaceNames) == 0 && !obj.Exported() {
+			continue
+		}
+		ifaceType, ok := obj.Type().Underlying().(*types.Interface)
+		if !ok {
+			if len(interfaceNames) > 0 {
+				return nil, fmt.Errorf("%v is not an interface", name)
+			}
+			continue
+		}
+		iface, err := c.interfaceFromTypes(name, ifaceType)
+		if err != nil {
+			return nil, err
+		}
+		if named, ok := obj.Type().(*types.Named); ok {
+			typeParams, err := c.typeParamsFromTypes(named.TypeParams())
+			if err != nil {
+				return nil, fmt.Errorf("interface %v: %v", name, err)
+			}
+			iface.TypeParams = typeParams
+		}
+		interfaces = append(interfaces, iface)
+	}
+
+	return &model.Package{
+		Name:       pkgName,
+		Interfaces: interfaces,
+	}, nil
+}
+
+// typeConverter turns go/types.Type values coming out of go/packages into
+// model.Type values, tracking the package that's doing the mocking so it can
+// tell apart identifiers that live in other packages.
+type typeConverter struct {
+	self *packages.Package
+}
+
+// interfaceFromTypes flattens it, including methods coming from embedded
+// interfaces, regardless of whether those embedded interfaces live in the
+// same file, a different file of the same package, or a different package
+// altogether. This replaces the old AST-based embedding resolution, which
+// could only see interfaces already parsed into the same *model.Package.
+func (c *typeConverter) interfaceFromTypes(name string, it *types.Interface) (*model.Interface, error) {
+	it = it.Complete()
+	methods := make([]*model.Method, it.NumMethods())
+	for i := 0; i < it.NumMethods(); i++ {
+		fn := it.Method(i)
+		sig := fn.Type().(*types.Signature)
+		m, err := c.methodFromTypes(fn.Name(), sig)
+		if err != nil {
+			return nil, fmt.Errorf("interface %v: %v", name, err)
+		}
+		methods[i] = m
+	}
+	return &model.Interface{Name: name, Methods: methods}, nil
+}
+
+func (c *typeConverter) methodFromTypes(name string, sig *types.Signature) (*model.Method, error) {
+	in, err := c.paramsFromTuple(sig.Params())
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.paramsFromTuple(sig.Results())
+	if err != nil {
+		return nil, err
+	}
+
+	m := &model.Method{Name: name, In: in, Out: out}
+	if sig.Variadic() && len(in) > 0 {
+		// go/types gives the variadic parameter its slice type []T; the model
+		// holds the element type T, and the "..." is added when rendering.
+		last := in[len(in)-1]
+		if slice, ok := last.Type.(*model.ArrayType); ok {
+			last = &model.Parameter{Name: last.Name, Type: slice.Type}
+		}
+		m.Variadic = last
+		m.In = in[:len(in)-1]
+	}
+	return m, nil
+}
+
+// typeParamsFromTypes converts a generic interface's type parameter list
+// (e.g. the "[T any]" in "type Repo[T any] interface {...}") into the
+// model.Parameter slice GenerateMockInterface uses to re-declare it on the
+// generated mock, verifier and expecter types. Returns nil for a
+// non-generic interface, where tparams is nil.
+func (c *typeConverter) typeParamsFromTypes(tparams *types.TypeParamList) ([]*model.Parameter, error) {
+	if tparams == nil {
+		return nil, nil
+	}
+	params := make([]*model.Parameter, tparams.Len())
+	for i := 0; i < tparams.Len(); i++ {
+		tp := tparams.At(i)
+		constraint, err := c.typeFromTypes(tp.Constraint())
+		if err != nil {
+			return nil, err
+		}
+		params[i] = &model.Parameter{Name: tp.Obj().Name(), Type: constraint}
+	}
+	return params, nil
+}
+
+func (c *typeConverter) paramsFromTuple(tuple *types.Tuple) ([]*model.Parameter, error) {
+	params := make([]*model.Parameter, tuple.Len())
+	for i := 0; i < tuple.Len(); i++ {
+		v := tuple.At(i)
+		t, err := c.typeFromTypes(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		params[i] = &model.Parameter{Name: v.Name(), Type: t}
+	}
+	return params, nil
+}
+
+func (c *typeConverter) typeFromTypes(t types.Type) (model.Type, error) {
+	switch t := t.(type) {
+	case *types.Basic:
+		return model.PredeclaredType(t.Name()), nil
+	case *types.TypeParam:
+		// A reference to a type parameter declared on the enclosing generic
+		// interface, e.g. the T in "Get(id string) (T, error)". It's in
+		// scope as a bare identifier wherever the mock/verifier/expecter
+		// re-declare that type parameter, so it needs no package qualifier.
+		return model.PredeclaredType(t.Obj().Name()), nil
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() == nil {
+			// Predeclared error, or similar.
+			return model.PredeclaredType(obj.Name()), nil
+		}
+		return &model.NamedType{Package: obj.Pkg().Path(), Type: obj.Name()}, nil
+	case *types.Pointer:
+		elem, err := c.typeFromTypes(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &model.PointerType{Type: elem}, nil
+	case *types.Slice:
+		elem, err := c.typeFromTypes(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &model.ArrayType{Len: -1, Type: elem}, nil
+	case *types.Array:
+		elem, err := c.typeFromTypes(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &model.ArrayType{Len: int(t.Len()), Type: elem}, nil
+	case *types.Map:
+		key, err := c.typeFromTypes(t.Key())
+		if err != nil {
+			return nil, err
+		}
+		value, err := c.typeFromTypes(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &model.MapType{Key: key, Value: value}, nil
+	case *types.Chan:
+		elem, err := c.typeFromTypes(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		dir := model.BothDir
+		switch t.Dir() {
+		case types.SendOnly:
+			dir = model.SendDir
+		case types.RecvOnly:
+			dir = model.RecvDir
+		}
+		return &model.ChanType{Dir: dir, Type: elem}, nil
+	case *types.Signature:
+		in, err := c.paramsFromTuple(t.Params())
+		if err != nil {
+			return nil, err
+		}
+		out, err := c.paramsFromTuple(t.Results())
+		if err != nil {
+			return nil, err
+		}
+		if t.Variadic() && len(in) > 0 {
+			// As for methods, FuncType keeps the variadic element type.
+			last := in[len(in)-1]
+			if slice, ok := last.Type.(*model.ArrayType); ok {
+				in[len(in)-1] = &model.Parameter{Name: last.Name, Type: slice.Type}
+			}
+		}
+		return &model.FuncType{In: in, Out: out, Variadic: t.Variadic()}, nil
+	case *types.Interface:
+		if t.NumMethods() == 0 {
+			return model.PredeclaredType("any"), nil
+		}
+		return model.PredeclaredType(strings.TrimSpace(t.String())), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T: %v", t, t)
+	}
+}
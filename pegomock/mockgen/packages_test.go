@@ -0,0 +1,35 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockgen
+
+import "testing"
+
+func TestParseFileRendersSlicesNotFixedArrays(t *testing.T) {
+	pkg, err := ParseFile("testdata/lister.go")
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if len(pkg.Interfaces) != 1 {
+		t.Fatalf("expected exactly one interface, got %v", pkg.Interfaces)
+	}
+	method := pkg.Interfaces[0].Methods[0]
+
+	if got := method.In[0].Type.String(nil, ""); got != "[]string" {
+		t.Fatalf("parameter rendered as %q, want %q", got, "[]string")
+	}
+	if got := method.Out[0].Type.String(nil, ""); got != "[]int" {
+		t.Fatalf("return value rendered as %q, want %q", got, "[]int")
+	}
+}
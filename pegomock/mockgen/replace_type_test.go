@@ -0,0 +1,64 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockgen
+
+import (
+	"testing"
+
+	"github.com/petergtz/pegomock/pegomock/mockgen/model"
+)
+
+func TestReplaceTypeRecursesIntoComposites(t *testing.T) {
+	g := &generator{replaceTypes: []TypeReplacement{
+		{SourcePackage: "internal/thing", DestPackage: "public/thing"},
+	}}
+	named := &model.NamedType{Package: "internal/thing", Type: "Thing"}
+
+	replacedPointer := g.replaceType(&model.PointerType{Type: named})
+	if got := replacedPointer.String(nil, ""); got != "*thing.Thing" {
+		t.Fatalf("pointer to replaced type rendered as %q", got)
+	}
+	if pkg := replacedPointer.(*model.PointerType).Type.(*model.NamedType).Package; pkg != "public/thing" {
+		t.Fatalf("pointer to replaced type kept package %q, want %q", pkg, "public/thing")
+	}
+
+	replacedSlice := g.replaceType(&model.ArrayType{Len: -1, Type: named})
+	if pkg := replacedSlice.(*model.ArrayType).Type.(*model.NamedType).Package; pkg != "public/thing" {
+		t.Fatalf("slice of replaced type kept package %q, want %q", pkg, "public/thing")
+	}
+}
+
+func TestCollectImportsReflectsReplacedTypes(t *testing.T) {
+	g := &generator{replaceTypes: []TypeReplacement{
+		{SourcePackage: "internal/thing", DestPackage: "public/thing"},
+	}}
+	pkg := &model.Package{
+		Interfaces: []*model.Interface{{
+			Name: "Store",
+			Methods: []*model.Method{{
+				Name: "Get",
+				Out:  []*model.Parameter{{Type: &model.PointerType{Type: &model.NamedType{Package: "internal/thing", Type: "Thing"}}}},
+			}},
+		}},
+	}
+
+	im := g.collectImports(pkg)
+	if im["internal/thing"] {
+		t.Fatalf("collectImports kept the substituted source package: %v", im)
+	}
+	if !im["public/thing"] {
+		t.Fatalf("collectImports is missing the destination package: %v", im)
+	}
+}
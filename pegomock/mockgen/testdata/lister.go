@@ -0,0 +1,7 @@
+package testdata
+
+// Lister is a fixture interface for exercising ParseFile's handling of
+// slice-typed parameters and return values.
+type Lister interface {
+	List(names []string) ([]int, error)
+}
@@ -0,0 +1,32 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnrollVariadicArgsDoesNotDuplicateFixedArgs(t *testing.T) {
+	g := new(generator)
+	callArgs := g.unrollVariadicArgs([]string{"ctx", "formats"})
+
+	if callArgs != "_s..." {
+		t.Fatalf("unrollVariadicArgs returned %q, want %q", callArgs, "_s...")
+	}
+	if !strings.Contains(g.buf.String(), "_s := []interface{}{ctx}") {
+		t.Fatalf("expected _s to be seeded only with the fixed args, got:\n%v", g.buf.String())
+	}
+}